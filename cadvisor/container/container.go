@@ -0,0 +1,52 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package container defines types for collecting container information.
+package container
+
+import (
+	"github.com/google/lmctfy/cadvisor/info"
+)
+
+type ListType int
+
+const (
+	LIST_SELF ListType = iota
+	LIST_RECURSIVE
+)
+
+// ContainerHandler knows how to collect spec and stats for a single
+// container, and how to enumerate its subcontainers.
+type ContainerHandler interface {
+	// Returns the static configuration for the container.
+	GetSpec() (*info.ContainerSpec, error)
+
+	// Returns the latest resource usage stats for the container.
+	GetStats() (*info.ContainerStats, error)
+
+	// Returns a rollup summary of recently collected stats.
+	StatsSummary() (*info.ContainerStatsSummary, error)
+
+	// Lists the names of the container's subcontainers.
+	ListContainers(listType ListType) ([]string, error)
+
+	// Returns the number of OOM and OOM-kill notifications seen since the
+	// last call.
+	GetOomEvents() (oom int, oomKill int, err error)
+
+	// Returns other names by which this container is known, e.g. the raw
+	// cgroup path backing a namespaced alias. Manager uses these to resolve
+	// lookups by any known name to the same container.
+	Aliases() ([]string, error)
+}
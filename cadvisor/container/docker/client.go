@@ -0,0 +1,93 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// dockerClient is a minimal client for the subset of the Docker remote API
+// this handler needs, talking to the daemon over its unix socket.
+type dockerClient struct {
+	httpClient *http.Client
+}
+
+func newDockerClient(socketPath string) *dockerClient {
+	return &dockerClient{
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				Dial: func(_, _ string) (net.Conn, error) {
+					return net.Dial("unix", socketPath)
+				},
+			},
+		},
+	}
+}
+
+type containerSummary struct {
+	Id string `json:"Id"`
+}
+
+// listContainers returns the ids of all running containers.
+func (self *dockerClient) listContainers() ([]string, error) {
+	var summaries []containerSummary
+	if err := self.get("/containers/json", &summaries); err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(summaries))
+	for _, s := range summaries {
+		ids = append(ids, s.Id)
+	}
+	return ids, nil
+}
+
+type containerConfig struct {
+	Image  string            `json:"Image"`
+	Labels map[string]string `json:"Labels"`
+	Env    []string          `json:"Env"`
+}
+
+type containerInspect struct {
+	Id      string          `json:"Id"`
+	Created time.Time       `json:"Created"`
+	Config  containerConfig `json:"Config"`
+}
+
+// inspectContainer returns the full configuration for a single container.
+func (self *dockerClient) inspectContainer(id string) (*containerInspect, error) {
+	var inspect containerInspect
+	if err := self.get(fmt.Sprintf("/containers/%s/json", id), &inspect); err != nil {
+		return nil, err
+	}
+	return &inspect, nil
+}
+
+func (self *dockerClient) get(path string, v interface{}) error {
+	// The host portion is irrelevant since we dial a unix socket above; the
+	// Docker daemon still requires a well-formed URL.
+	resp, err := self.httpClient.Get("http://unix" + path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("docker daemon returned %d for %q", resp.StatusCode, path)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
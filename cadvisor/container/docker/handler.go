@@ -0,0 +1,137 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package docker implements a container.ContainerHandlerFactory that
+// resolves "/docker/<id>" names against the Docker daemon.
+package docker
+
+import (
+	"flag"
+	"strings"
+	"sync"
+
+	"github.com/google/lmctfy/cadvisor/container"
+	"github.com/google/lmctfy/cadvisor/info"
+)
+
+var dockerSocket = flag.String("docker_socket", "/var/run/docker.sock", "path to the Docker daemon's unix socket")
+
+var (
+	clientOnce sync.Once
+	client     *dockerClient
+)
+
+// getClient lazily builds the Docker client from --docker_socket. Deferred
+// to first use (rather than built in init()) since flags aren't parsed yet
+// when init() runs.
+func getClient() *dockerClient {
+	clientOnce.Do(func() {
+		client = newDockerClient(*dockerSocket)
+	})
+	return client
+}
+
+const namePrefix = "/docker/"
+
+// dockerContainerHandler wraps the raw cgroup handler for a container's
+// cgroup, enriching its spec with the image, labels, env and creation time
+// reported by the Docker daemon.
+type dockerContainerHandler struct {
+	container.ContainerHandler
+
+	client *dockerClient
+	id     string
+	name   string
+}
+
+func newDockerContainerHandler(client *dockerClient, name string) (container.ContainerHandler, error) {
+	id := strings.TrimPrefix(name, namePrefix)
+
+	// Assumes the cgroupfs driver, where the Docker daemon places each
+	// container's cgroup at /docker/<id>.
+	raw, err := container.NewRawContainerHandler(namePrefix + id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dockerContainerHandler{
+		ContainerHandler: raw,
+		client:           client,
+		id:               id,
+		name:             name,
+	}, nil
+}
+
+func (self *dockerContainerHandler) GetSpec() (*info.ContainerSpec, error) {
+	spec, err := self.ContainerHandler.GetSpec()
+	if err != nil {
+		return nil, err
+	}
+
+	inspect, err := self.client.inspectContainer(self.id)
+	if err != nil {
+		return nil, err
+	}
+	spec.CreationTime = inspect.Created
+	spec.Image = inspect.Config.Image
+	spec.Labels = inspect.Config.Labels
+	spec.Env = make(map[string]string, len(inspect.Config.Env))
+	for _, entry := range inspect.Config.Env {
+		if key, value, ok := splitEnv(entry); ok {
+			spec.Env[key] = value
+		}
+	}
+	return spec, nil
+}
+
+func (self *dockerContainerHandler) Aliases() ([]string, error) {
+	return []string{namePrefix + self.id}, nil
+}
+
+func splitEnv(entry string) (key, value string, ok bool) {
+	parts := strings.SplitN(entry, "=", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// dockerFactory claims any name in the "/docker/<id>" namespace.
+type dockerFactory struct{}
+
+func (self *dockerFactory) CanHandle(name string) bool {
+	return strings.HasPrefix(name, namePrefix)
+}
+
+func (self *dockerFactory) NewContainerHandler(name string) (container.ContainerHandler, error) {
+	return newDockerContainerHandler(getClient(), name)
+}
+
+// ListContainers returns "/docker/<id>" for every container the daemon
+// currently reports as running.
+func (self *dockerFactory) ListContainers() ([]string, error) {
+	ids, err := getClient().listContainers()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(ids))
+	for _, id := range ids {
+		names = append(names, namePrefix+id)
+	}
+	return names, nil
+}
+
+func init() {
+	container.RegisterContainerHandlerFactory(&dockerFactory{})
+}
@@ -0,0 +1,90 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// ContainerHandlerFactory lets a container source (raw cgroups, Docker,
+// systemd, ...) plug into the manager without the manager knowing about it
+// directly. Sources register a factory via RegisterContainerHandlerFactory,
+// typically from an init() in the source's package.
+type ContainerHandlerFactory interface {
+	// Whether this factory can create a handler for the given (possibly
+	// namespaced) container name.
+	CanHandle(name string) bool
+
+	// Creates a handler for the given container name. Only called after
+	// CanHandle(name) returned true.
+	NewContainerHandler(name string) (ContainerHandler, error)
+
+	// Lists the namespaced names of all containers this factory currently
+	// knows about, e.g. by querying a daemon or walking a cgroup tree.
+	ListContainers() ([]string, error)
+}
+
+var (
+	factoriesLock sync.RWMutex
+	factories     []ContainerHandlerFactory
+)
+
+// RegisterContainerHandlerFactory adds a factory to the registry. Factories
+// are tried most-recently-registered first, so a specific source (Docker,
+// systemd) registered after the catch-all raw factory gets first refusal on
+// names it recognizes.
+func RegisterContainerHandlerFactory(factory ContainerHandlerFactory) {
+	factoriesLock.Lock()
+	defer factoriesLock.Unlock()
+	factories = append(factories, factory)
+}
+
+// NewContainerHandler returns a ContainerHandler for the given (possibly
+// namespaced) container name, from whichever registered factory claims it.
+func NewContainerHandler(name string) (ContainerHandler, error) {
+	factoriesLock.RLock()
+	defer factoriesLock.RUnlock()
+
+	for i := len(factories) - 1; i >= 0; i-- {
+		if factories[i].CanHandle(name) {
+			return factories[i].NewContainerHandler(name)
+		}
+	}
+	return nil, fmt.Errorf("no container handler factory registered for container %q", name)
+}
+
+// AllContainerNames merges the container names reported by every registered
+// factory, for manager.detectContainers to diff against what it already
+// knows about. A factory that fails (e.g. Docker's socket being absent or
+// momentarily unreachable) is logged and skipped rather than failing the
+// whole listing, so one down source doesn't stop every other container from
+// being detected.
+func AllContainerNames() ([]string, error) {
+	factoriesLock.RLock()
+	defer factoriesLock.RUnlock()
+
+	var names []string
+	for _, factory := range factories {
+		factoryNames, err := factory.ListContainers()
+		if err != nil {
+			log.Printf("Failed to list containers from a container handler factory: %s", err)
+			continue
+		}
+		names = append(names, factoryNames...)
+	}
+	return names, nil
+}
@@ -0,0 +1,442 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package container
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/lmctfy/cadvisor/info"
+)
+
+// cgroupRoot is where the cgroup subsystems this handler reads are assumed
+// to be mounted.
+const cgroupRoot = "/sys/fs/cgroup"
+
+// clockTicksPerSecond is the USER_HZ value cpuacct.stat's "user"/"system"
+// fields are reported in on essentially every Linux distribution.
+const clockTicksPerSecond = 100
+
+// rawContainerHandler handles containers identified by their raw cgroup
+// path (e.g. "/docker/deadbeef").
+type rawContainerHandler struct {
+	name string
+}
+
+// NewRawContainerHandler returns a ContainerHandler that reads the cgroup at
+// the given path directly. Other handlers (e.g. docker) that have already
+// resolved a namespaced name to its backing cgroup use this to avoid going
+// back through the factory registry.
+func NewRawContainerHandler(name string) (ContainerHandler, error) {
+	return &rawContainerHandler{
+		name: name,
+	}, nil
+}
+
+// cgroupPath returns the path to a file under the given cgroup subsystem for
+// this container, e.g. cgroupPath("cpuacct", "cpuacct.usage").
+func (self *rawContainerHandler) cgroupPath(subsystem, file string) string {
+	return filepath.Join(cgroupRoot, subsystem, self.name, file)
+}
+
+// readCgroupUint reads a cgroup file holding a single unsigned integer.
+func (self *rawContainerHandler) readCgroupUint(subsystem, file string) (uint64, error) {
+	raw, err := ioutil.ReadFile(self.cgroupPath(subsystem, file))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(raw)), 10, 64)
+}
+
+func (self *rawContainerHandler) GetSpec() (*info.ContainerSpec, error) {
+	// TODO(vmarmol): Read cgroup files to populate cpu/memory limits.
+	return &info.ContainerSpec{}, nil
+}
+
+func (self *rawContainerHandler) GetStats() (*info.ContainerStats, error) {
+	stats := &info.ContainerStats{
+		Timestamp: time.Now(),
+	}
+
+	cpu, err := self.readCpuStats()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cpu stats for container %q: %s", self.name, err)
+	}
+	stats.Cpu = *cpu
+
+	memory, err := self.readMemoryStats()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read memory stats for container %q: %s", self.name, err)
+	}
+	stats.Memory = *memory
+
+	// Network and filesystem stats are best-effort: neither subsystem
+	// exposes them the moment a container is created (no process yet, no
+	// blkio activity yet), and that shouldn't fail the whole sample.
+	if network, err := self.readNetworkStats(); err != nil {
+		log.Printf("Failed to read network stats for container %q: %s", self.name, err)
+	} else {
+		stats.Network = *network
+	}
+	stats.Filesystem = self.readFilesystemStats()
+
+	return stats, nil
+}
+
+// readCpuStats reads usage counters from the cpuacct cgroup.
+func (self *rawContainerHandler) readCpuStats() (*info.CpuStats, error) {
+	var stats info.CpuStats
+
+	total, err := self.readCgroupUint("cpuacct", "cpuacct.usage")
+	if err != nil {
+		return nil, err
+	}
+	stats.Usage.Total = total
+
+	if raw, err := ioutil.ReadFile(self.cgroupPath("cpuacct", "cpuacct.usage_percpu")); err == nil {
+		for _, field := range strings.Fields(string(raw)) {
+			if usage, err := strconv.ParseUint(field, 10, 64); err == nil {
+				stats.Usage.PerCpu = append(stats.Usage.PerCpu, usage)
+			}
+		}
+	}
+
+	if raw, err := ioutil.ReadFile(self.cgroupPath("cpuacct", "cpuacct.stat")); err == nil {
+		scanner := bufio.NewScanner(strings.NewReader(string(raw)))
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) != 2 {
+				continue
+			}
+			ticks, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			nanos := ticks * uint64(time.Second) / clockTicksPerSecond
+			switch fields[0] {
+			case "user":
+				stats.Usage.User = nanos
+			case "system":
+				stats.Usage.System = nanos
+			}
+		}
+	}
+
+	return &stats, nil
+}
+
+// readMemoryStats reads usage counters from the memory cgroup.
+func (self *rawContainerHandler) readMemoryStats() (*info.MemoryStats, error) {
+	var stats info.MemoryStats
+
+	usage, err := self.readCgroupUint("memory", "memory.usage_in_bytes")
+	if err != nil {
+		return nil, err
+	}
+	stats.Usage = usage
+
+	var inactiveFile uint64
+	if raw, err := ioutil.ReadFile(self.cgroupPath("memory", "memory.stat")); err == nil {
+		scanner := bufio.NewScanner(strings.NewReader(string(raw)))
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) != 2 {
+				continue
+			}
+			value, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			switch fields[0] {
+			case "cache":
+				stats.Cache = value
+			case "total_inactive_file":
+				inactiveFile = value
+			case "pgfault":
+				stats.ContainerData.Pgfault = value
+				stats.HierarchicalData.Pgfault = value
+			case "pgmajfault":
+				stats.ContainerData.Pgmajfault = value
+				stats.HierarchicalData.Pgmajfault = value
+			}
+		}
+	}
+
+	// The working set excludes reclaimable (inactive) page cache.
+	if usage > inactiveFile {
+		stats.WorkingSet = usage - inactiveFile
+	} else {
+		stats.WorkingSet = usage
+	}
+
+	return &stats, nil
+}
+
+// readNetworkStats reports the counters for every non-loopback interface
+// visible to a process in this container, read from /proc/<pid>/net/dev.
+// No cgroup subsystem exposes network counters directly, so this relies on
+// a pid from the cgroup's process list instead.
+func (self *rawContainerHandler) readNetworkStats() (*info.NetworkStats, error) {
+	pid, err := self.firstPid()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filepath.Join("/proc", strconv.Itoa(pid), "net", "dev"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var network info.NetworkStats
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		name := strings.TrimSpace(parts[0])
+		if name == "lo" {
+			continue
+		}
+		fields := strings.Fields(parts[1])
+		if len(fields) < 16 {
+			continue
+		}
+
+		iface := info.InterfaceStats{Name: name}
+		iface.RxBytes, _ = strconv.ParseUint(fields[0], 10, 64)
+		iface.RxPackets, _ = strconv.ParseUint(fields[1], 10, 64)
+		iface.RxErrors, _ = strconv.ParseUint(fields[2], 10, 64)
+		iface.RxDropped, _ = strconv.ParseUint(fields[3], 10, 64)
+		iface.TxBytes, _ = strconv.ParseUint(fields[8], 10, 64)
+		iface.TxPackets, _ = strconv.ParseUint(fields[9], 10, 64)
+		iface.TxErrors, _ = strconv.ParseUint(fields[10], 10, 64)
+		iface.TxDropped, _ = strconv.ParseUint(fields[11], 10, 64)
+		network.Interfaces = append(network.Interfaces, iface)
+	}
+	return &network, nil
+}
+
+// firstPid returns a pid currently running in this container's cgroup.
+func (self *rawContainerHandler) firstPid() (int, error) {
+	raw, err := ioutil.ReadFile(self.cgroupPath("cpuacct", "cgroup.procs"))
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(raw))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("no pids in cgroup %q", self.name)
+	}
+	return strconv.Atoi(fields[0])
+}
+
+// blkioCounts holds the read/write total for one device, as reported by a
+// blkio.throttle.io_* file.
+type blkioCounts struct {
+	read, write uint64
+}
+
+// readFilesystemStats reads per-device block IO counters from the blkio
+// cgroup, plus on-disk usage via statfs on the mount point backing each
+// device. A container with no blkio activity yet has no throttle files to
+// read, which isn't an error - it just reports no filesystem stats.
+func (self *rawContainerHandler) readFilesystemStats() []info.FsStats {
+	bytesByDevice, err := readBlkioTotals(self.cgroupPath("blkio", "blkio.throttle.io_service_bytes"))
+	if err != nil {
+		return nil
+	}
+	iopsByDevice, err := readBlkioTotals(self.cgroupPath("blkio", "blkio.throttle.io_serviced"))
+	if err != nil {
+		iopsByDevice = nil
+	}
+	serviceTimeByDevice, err := readBlkioTotals(self.cgroupPath("blkio", "blkio.throttle.io_service_time"))
+	if err != nil {
+		serviceTimeByDevice = nil
+	}
+
+	var stats []info.FsStats
+	for majMin := range bytesByDevice {
+		device, err := resolveBlockDevice(majMin)
+		if err != nil {
+			continue
+		}
+		iops := iopsByDevice[majMin]
+		serviceTime := serviceTimeByDevice[majMin]
+
+		usage, err := readDeviceUsage(device)
+		if err != nil {
+			log.Printf("Failed to statfs a mount point backing device %q: %s", device, err)
+		}
+
+		stats = append(stats, info.FsStats{
+			Device: device,
+			Usage:  usage,
+
+			ReadsCompleted:  iops.read,
+			WritesCompleted: iops.write,
+			// TODO(vmarmol): blkio.throttle.io_service_bytes only reports
+			// bytes, not a true per-container sector count; /proc/diskstats
+			// has real sector counts but only at whole-machine, not
+			// per-cgroup, granularity. Leave unset rather than relabeling
+			// a byte count as a sector count.
+
+			IoTime: (serviceTime.read + serviceTime.write) / uint64(time.Millisecond),
+		})
+	}
+	return stats
+}
+
+// readDeviceUsage statfs's the mount point backing device to report bytes
+// actually used on disk; neither blkio nor any other cgroup subsystem
+// exposes that directly.
+func readDeviceUsage(device string) (uint64, error) {
+	mountpoint, err := deviceMountpoint(device)
+	if err != nil {
+		return 0, err
+	}
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(mountpoint, &stat); err != nil {
+		return 0, err
+	}
+	return (stat.Blocks - stat.Bfree) * uint64(stat.Bsize), nil
+}
+
+// deviceMountpoint returns the mount point of the first filesystem in
+// /proc/mounts backed by device or one of its partitions (blkio accounts at
+// the whole-device level, but most devices are mounted via a partition).
+func deviceMountpoint(device string) (string, error) {
+	raw, err := ioutil.ReadFile("/proc/mounts")
+	if err != nil {
+		return "", err
+	}
+	scanner := bufio.NewScanner(strings.NewReader(string(raw)))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		if strings.HasPrefix(fields[0], device) {
+			return fields[1], nil
+		}
+	}
+	return "", fmt.Errorf("no mount point found for device %q", device)
+}
+
+// readBlkioTotals parses a blkio.throttle.io_* file. Each line is
+// "<major>:<minor> <Read|Write|Sync|Async|Total> <value>"; the per-device
+// "Total" line and the trailing grand-total line are ignored.
+func readBlkioTotals(path string) (map[string]blkioCounts, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make(map[string]blkioCounts)
+	scanner := bufio.NewScanner(strings.NewReader(string(raw)))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			continue
+		}
+		majMin, op := fields[0], fields[1]
+		value, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		counts := totals[majMin]
+		switch op {
+		case "Read":
+			counts.read = value
+		case "Write":
+			counts.write = value
+		default:
+			continue
+		}
+		totals[majMin] = counts
+	}
+	return totals, nil
+}
+
+// resolveBlockDevice maps a "<major>:<minor>" pair, as reported by the
+// blkio cgroup, to the same "/dev/<name>" form getFilesystems uses, via the
+// /sys/dev/block symlink - keeping the two in sync is what lets
+// attributeFsCapacity match a stat sample back to its device's capacity.
+func resolveBlockDevice(majMin string) (string, error) {
+	link, err := os.Readlink(filepath.Join("/sys/dev/block", majMin))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join("/dev", filepath.Base(link)), nil
+}
+
+func (self *rawContainerHandler) StatsSummary() (*info.ContainerStatsSummary, error) {
+	return &info.ContainerStatsSummary{}, nil
+}
+
+func (self *rawContainerHandler) ListContainers(listType ListType) ([]string, error) {
+	// TODO(vmarmol): Walk the cgroup hierarchy under self.name.
+	return nil, nil
+}
+
+func (self *rawContainerHandler) GetOomEvents() (int, int, error) {
+	// TODO(vmarmol): Tail the memory cgroup's "memory.oom_control" event fd.
+	return 0, 0, nil
+}
+
+func (self *rawContainerHandler) Aliases() ([]string, error) {
+	return nil, nil
+}
+
+// rawFactory is the fallback ContainerHandlerFactory: it handles any
+// container name not claimed by a more specific namespace (docker, systemd,
+// ...), including the explicit "/raw/<cgroup>" namespace.
+type rawFactory struct{}
+
+func (self *rawFactory) CanHandle(name string) bool {
+	return true
+}
+
+func (self *rawFactory) NewContainerHandler(name string) (ContainerHandler, error) {
+	// "/raw/<cgroup>" names the cgroup "/<cgroup>".
+	if strings.HasPrefix(name, "/raw/") {
+		name = strings.TrimPrefix(name, "/raw")
+	}
+	return NewRawContainerHandler(name)
+}
+
+func (self *rawFactory) ListContainers() ([]string, error) {
+	root, err := NewRawContainerHandler("/")
+	if err != nil {
+		return nil, err
+	}
+	names, err := root.ListContainers(LIST_RECURSIVE)
+	if err != nil {
+		return nil, err
+	}
+	return append(names, "/"), nil
+}
+
+func init() {
+	RegisterContainerHandlerFactory(&rawFactory{})
+}
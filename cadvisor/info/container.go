@@ -0,0 +1,216 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package info
+
+import (
+	"time"
+)
+
+// ContainerReference uniquely identifies a container in the system.
+type ContainerReference struct {
+	// The absolute name of the container.
+	Name string
+}
+
+type CpuSpec struct {
+	Limit    uint64 `json:"limit"`
+	MaxLimit uint64 `json:"max_limit"`
+	Mask     string `json:"mask,omitempty"`
+}
+
+type MemorySpec struct {
+	// The amount of memory requested. Default is unlimited (0).
+	// Units: bytes.
+	Limit uint64 `json:"limit,omitempty"`
+
+	// The amount of guaranteed memory. Default is unlimited (0).
+	// Units: bytes.
+	Reservation uint64 `json:"reservation,omitempty"`
+
+	// The amount of swap space requested. Default is unlimited (0).
+	// Units: bytes.
+	SwapLimit uint64 `json:"swap_limit,omitempty"`
+}
+
+// ContainerSpec describes the static configuration of a container.
+type ContainerSpec struct {
+	CreationTime time.Time `json:"creation_time,omitempty"`
+
+	Cpu    *CpuSpec    `json:"cpu,omitempty"`
+	Memory *MemorySpec `json:"memory,omitempty"`
+
+	// The following are populated for containers backed by an image-based
+	// runtime (e.g. Docker); they are empty for raw cgroups.
+	Image  string            `json:"image,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Env    map[string]string `json:"env,omitempty"`
+}
+
+type CpuStats struct {
+	Usage struct {
+		// Total CPU usage.
+		// Units: nanoseconds.
+		Total uint64 `json:"total"`
+
+		// Per CPU/core usage of the container.
+		// Units: nanoseconds.
+		PerCpu []uint64 `json:"per_cpu_usage,omitempty"`
+
+		// Time spent in user space.
+		// Units: nanoseconds.
+		User uint64 `json:"user"`
+
+		// Time spent in kernel space.
+		// Units: nanoseconds.
+		System uint64 `json:"system"`
+	} `json:"usage"`
+}
+
+type MemoryStats struct {
+	// Current memory usage.
+	// Units: bytes.
+	Usage uint64 `json:"usage"`
+
+	// Number of bytes of page cache memory.
+	// Units: bytes.
+	Cache uint64 `json:"cache"`
+
+	// The amount of working set memory, this includes recently accessed
+	// memory, dirty memory, and kernel memory.
+	// Units: bytes.
+	WorkingSet uint64 `json:"working_set"`
+
+	ContainerData    MemoryStatsMemoryData `json:"container_data,omitempty"`
+	HierarchicalData MemoryStatsMemoryData `json:"hierarchical_data,omitempty"`
+}
+
+type MemoryStatsMemoryData struct {
+	Pgfault    uint64 `json:"pgfault"`
+	Pgmajfault uint64 `json:"pgmajfault"`
+}
+
+// InterfaceStats are the network counters for a single interface, as seen
+// from inside the container's network namespace.
+type InterfaceStats struct {
+	Name string `json:"name"`
+
+	RxBytes   uint64 `json:"rx_bytes"`
+	RxPackets uint64 `json:"rx_packets"`
+	RxErrors  uint64 `json:"rx_errors"`
+	RxDropped uint64 `json:"rx_dropped"`
+
+	TxBytes   uint64 `json:"tx_bytes"`
+	TxPackets uint64 `json:"tx_packets"`
+	TxErrors  uint64 `json:"tx_errors"`
+	TxDropped uint64 `json:"tx_dropped"`
+}
+
+// NetworkStats holds per-interface network counters.
+type NetworkStats struct {
+	Interfaces []InterfaceStats `json:"interfaces,omitempty"`
+}
+
+// FsStats are the filesystem counters for a single device backing the
+// container, combining blkio cgroup counters with a statfs of the mount
+// point.
+type FsStats struct {
+	// The block device, e.g. "/dev/sda".
+	Device string `json:"device"`
+
+	// Units: bytes.
+	Capacity uint64 `json:"capacity"`
+	Usage    uint64 `json:"usage"`
+
+	ReadsCompleted  uint64 `json:"reads_completed"`
+	SectorsRead     uint64 `json:"sectors_read"`
+	WritesCompleted uint64 `json:"writes_completed"`
+	SectorsWritten  uint64 `json:"sectors_written"`
+
+	// Cumulative milliseconds spent doing I/Os on this device.
+	IoTime uint64 `json:"io_time"`
+}
+
+// ContainerStats is a sample of container resource usage at a point in time.
+type ContainerStats struct {
+	// The time at which this sample was collected.
+	Timestamp time.Time `json:"timestamp"`
+
+	Cpu        CpuStats     `json:"cpu,omitempty"`
+	Memory     MemoryStats  `json:"memory,omitempty"`
+	Network    NetworkStats `json:"network,omitempty"`
+	Filesystem []FsStats    `json:"filesystem,omitempty"`
+}
+
+// FsStatsSummary is the average I/O rate for a device over a window of
+// samples.
+type FsStatsSummary struct {
+	Device string `json:"device"`
+
+	// Average bytes/sec read+written over the sample window.
+	AvgIoBytesPerSecond float64 `json:"avg_io_bytes_per_second"`
+}
+
+// ContainerStatsSummary holds a rollup of recently collected stats.
+type ContainerStatsSummary struct {
+	// Latest sample collected.
+	Latest *ContainerStats `json:"latest,omitempty"`
+
+	// Max memory usage observed in the collected window.
+	MaxMemoryUsage uint64 `json:"max_memory_usage,omitempty"`
+
+	// Average filesystem I/O rate per device over the collected window.
+	Filesystems []FsStatsSummary `json:"filesystems,omitempty"`
+}
+
+// ContainerInfo is the complete view of a container the manager exposes to
+// API callers.
+type ContainerInfo struct {
+	// The absolute name of the container.
+	Name string `json:"name"`
+
+	// Subcontainers of this container.
+	Subcontainers []string `json:"subcontainers,omitempty"`
+
+	// The container's static configuration.
+	Spec *ContainerSpec `json:"spec,omitempty"`
+
+	// Samples collected for this container.
+	Stats []*ContainerStats `json:"stats,omitempty"`
+
+	// Rollup of the collected stats.
+	StatsSummary *ContainerStatsSummary `json:"stats_summary,omitempty"`
+}
+
+// FsInfo describes a block device discovered on the machine.
+type FsInfo struct {
+	// The block device, e.g. "/dev/sda".
+	Device string `json:"device"`
+
+	// Units: bytes.
+	Capacity uint64 `json:"capacity"`
+}
+
+// MachineInfo describes the machine the manager is running on.
+type MachineInfo struct {
+	// The number of cores on this machine.
+	NumCores int `json:"num_cores"`
+
+	// The amount of memory on this machine.
+	// Units: bytes.
+	MemoryCapacity int64 `json:"memory_capacity"`
+
+	// Block devices present on the machine.
+	Filesystems []FsInfo `json:"filesystems,omitempty"`
+}
@@ -0,0 +1,60 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package info
+
+import (
+	"time"
+)
+
+type EventType int
+
+const (
+	EventContainerCreation EventType = iota
+	EventContainerDeletion
+	EventOom
+	EventOomKill
+)
+
+// Event is a single occurrence reported by the manager's event subsystem.
+type Event struct {
+	// The container the event pertains to.
+	ContainerName string `json:"container_name"`
+
+	// The time at which the event occurred.
+	Timestamp time.Time `json:"timestamp"`
+
+	EventType EventType `json:"event_type"`
+}
+
+// EventRequest describes the set of events a watcher is interested in.
+type EventRequest struct {
+	// Name of the container to watch. Empty means the root container.
+	ContainerName string
+
+	// If true, also watch all subcontainers of ContainerName.
+	IncludeSubcontainers bool
+
+	// The set of event types to watch for. A nil or empty map matches all
+	// event types.
+	EventTypes map[EventType]bool
+
+	// If non-zero, events at or after StartTime that are still held in the
+	// manager's history are replayed to the watcher before live events.
+	StartTime time.Time
+
+	// If non-zero, only events at or before EndTime are replayed; live events are
+	// not delivered once EndTime has passed.
+	EndTime time.Time
+}
@@ -0,0 +1,34 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package info
+
+import (
+	"time"
+)
+
+// StatsRequest describes a query for a container's historical stats.
+type StatsRequest struct {
+	// Samples with timestamp >= Start are eligible. Zero means no lower
+	// bound.
+	Start time.Time
+
+	// Samples with timestamp <= End are eligible. Zero means no upper bound.
+	End time.Time
+
+	// The maximum number of samples to return. If Start and End are both
+	// zero, this returns the NumStats most recent samples. Zero means no
+	// limit.
+	NumStats int
+}
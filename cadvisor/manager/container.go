@@ -17,14 +17,15 @@
 package manager
 
 import (
-	"container/list"
 	"flag"
 	"log"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/google/lmctfy/cadvisor/container"
 	"github.com/google/lmctfy/cadvisor/info"
+	"github.com/google/lmctfy/cadvisor/storage"
 )
 
 var historyDuration = flag.Int("history_duration", 60, "number of seconds of container history to keep")
@@ -34,12 +35,84 @@ type containerStat struct {
 	Timestamp time.Time
 	Data      *info.ContainerStats
 }
+
+// statsBuffer holds a bounded history of samples, oldest first. Samples are
+// always appended in increasing timestamp order, so range queries can use
+// binary search instead of a linear scan.
+type statsBuffer struct {
+	buffer  []*containerStat
+	maxSize int
+}
+
+func newStatsBuffer(maxSize int) *statsBuffer {
+	return &statsBuffer{
+		maxSize: maxSize,
+	}
+}
+
+func (self *statsBuffer) Len() int {
+	return len(self.buffer)
+}
+
+// Add appends a sample, evicting the oldest sample if the buffer is full.
+func (self *statsBuffer) Add(stat *containerStat) {
+	self.buffer = append(self.buffer, stat)
+	if len(self.buffer) > self.maxSize {
+		self.buffer = self.buffer[len(self.buffer)-self.maxSize:]
+	}
+}
+
+// Get returns the samples satisfying the request. With a zero Start and End
+// it returns the NumStats most recent samples; otherwise it returns samples
+// whose timestamp falls in [Start, End], capped to the NumStats most recent
+// of those if NumStats is set.
+func (self *statsBuffer) Get(request info.StatsRequest) []*containerStat {
+	if request.Start.IsZero() && request.End.IsZero() {
+		return self.recent(request.NumStats)
+	}
+
+	startIndex := len(self.buffer)
+	if !request.Start.IsZero() {
+		startIndex = sort.Search(len(self.buffer), func(i int) bool {
+			return !self.buffer[i].Timestamp.Before(request.Start)
+		})
+	} else {
+		startIndex = 0
+	}
+	endIndex := len(self.buffer)
+	if !request.End.IsZero() {
+		endIndex = sort.Search(len(self.buffer), func(i int) bool {
+			return self.buffer[i].Timestamp.After(request.End)
+		})
+	}
+	if startIndex >= endIndex {
+		return nil
+	}
+	result := self.buffer[startIndex:endIndex]
+	if request.NumStats > 0 && len(result) > request.NumStats {
+		result = result[len(result)-request.NumStats:]
+	}
+	return result
+}
+
+// recent returns the n most recent samples, or all samples if n <= 0.
+func (self *statsBuffer) recent(n int) []*containerStat {
+	if n <= 0 || n > len(self.buffer) {
+		n = len(self.buffer)
+	}
+	return self.buffer[len(self.buffer)-n:]
+}
+
 type containerInfo struct {
 	Name          string
 	Subcontainers []string
 	Spec          *info.ContainerSpec
-	Stats         *list.List
+	Stats         *statsBuffer
 	StatsSummary  *info.ContainerStatsSummary
+
+	// The housekeeping interval currently in effect for this container.
+	// Exposed for debugging; see --allow_dynamic_housekeeping.
+	HousekeepingInterval time.Duration
 }
 
 type containerData struct {
@@ -47,7 +120,20 @@ type containerData struct {
 	info    containerInfo
 	lock    sync.Mutex
 
-	// Tells the container to stop.
+	// Bus to publish OOM events detected for this container to.
+	events *eventManager
+
+	// Stats backends (the manager's in-memory driver plus any external ones)
+	// stats are additionally written to, on top of the in-memory window held
+	// in info.Stats.
+	storageDrivers []storage.StorageDriver
+
+	// The machine's block devices, used to attribute per-device I/O in
+	// collected stats to the right disk.
+	machineInfo *info.MachineInfo
+
+	// Closed to tell the container's goroutines (housekeeping, oomWatcher)
+	// to stop.
 	stop chan bool
 }
 
@@ -56,12 +142,20 @@ func (c *containerData) Start() error {
 	c.housekeepingTick()
 	log.Printf("Start housekeeping for container %q\n", c.info.Name)
 
+	c.lock.Lock()
+	c.info.HousekeepingInterval = *minHousekeepingInterval
+	c.lock.Unlock()
+
 	go c.housekeeping()
+	go c.oomWatcher()
 	return nil
 }
 
 func (c *containerData) Stop() error {
-	c.stop <- true
+	// Closing (rather than sending a single value) wakes every receiver on
+	// c.stop - both housekeeping and oomWatcher select on it - instead of
+	// leaking whichever goroutine doesn't win the single delivery.
+	close(c.stop)
 	return nil
 }
 
@@ -84,7 +178,7 @@ func (c *containerData) GetInfo() (*containerInfo, error) {
 	return &ret, nil
 }
 
-func NewContainerData(containerName string) (*containerData, error) {
+func NewContainerData(containerName string, events *eventManager, storageDrivers []storage.StorageDriver, machineInfo *info.MachineInfo) (*containerData, error) {
 	cont := &containerData{}
 	handler, err := container.NewContainerHandler(containerName)
 	if err != nil {
@@ -92,36 +186,94 @@ func NewContainerData(containerName string) (*containerData, error) {
 	}
 	cont.handler = handler
 	cont.info.Name = containerName
-	cont.info.Stats = list.New()
-	cont.stop = make(chan bool, 1)
+	cont.info.Stats = newStatsBuffer(*historyDuration)
+	cont.events = events
+	cont.storageDrivers = storageDrivers
+	cont.machineInfo = machineInfo
+	cont.stop = make(chan bool)
 
 	return cont, nil
 }
 
+// housekeeping samples the container on its own schedule rather than a
+// shared ticker, so that many containers don't all wake up on the same
+// second boundary. The interval is jittered on every tick and, when
+// --allow_dynamic_housekeeping is set, grows towards
+// --max_housekeeping_interval while the container's stats are unchanged and
+// shrinks back towards --min_housekeeping_interval as soon as they aren't.
 func (c *containerData) housekeeping() {
-	// Housekeep every second.
-	for true {
+	for {
+		c.lock.Lock()
+		interval := c.info.HousekeepingInterval
+		c.lock.Unlock()
+
+		timer := time.NewTimer(jitter(interval))
 		select {
 		case <-c.stop:
 			// Stop housekeeping when signaled.
+			timer.Stop()
 			return
-		case <-time.Tick(time.Second):
+		case <-timer.C:
 			start := time.Now()
-			c.housekeepingTick()
+			changed := c.housekeepingTick()
 
 			// Log if housekeeping took longer than 120ms.
 			duration := time.Since(start)
 			if duration >= 120*time.Millisecond {
 				log.Printf("Housekeeping(%s) took %s", c.info.Name, duration)
 			}
+
+			c.lock.Lock()
+			c.info.HousekeepingInterval = nextHousekeepingInterval(interval, changed)
+			c.lock.Unlock()
 		}
 	}
 }
 
-func (c *containerData) housekeepingTick() {
-	err := c.updateStats()
+// housekeepingTick collects a fresh stats sample and reports whether it
+// differs meaningfully from the previous one.
+func (c *containerData) housekeepingTick() bool {
+	changed, err := c.updateStats()
 	if err != nil {
 		log.Printf("Failed to update stats for container \"%s\": %s", c.info.Name, err)
+		// Treat a failed sample as "changed" so we retry at the short
+		// interval rather than backing off while something is broken.
+		return true
+	}
+	return changed
+}
+
+// oomWatcher tails the container's OOM notifications and publishes them on
+// the event bus. It polls the handler's GetOomEvents once a second.
+func (c *containerData) oomWatcher() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			oom, kill, err := c.handler.GetOomEvents()
+			if err != nil {
+				log.Printf("Failed to check OOM events for container \"%s\": %s", c.info.Name, err)
+				continue
+			}
+			for i := 0; i < oom; i++ {
+				c.events.AddEvent(&info.Event{
+					ContainerName: c.info.Name,
+					Timestamp:     time.Now(),
+					EventType:     info.EventOom,
+				})
+			}
+			for i := 0; i < kill; i++ {
+				c.events.AddEvent(&info.Event{
+					ContainerName: c.info.Name,
+					Timestamp:     time.Now(),
+					EventType:     info.EventOomKill,
+				})
+			}
+		}
 	}
 }
 
@@ -136,32 +288,128 @@ func (c *containerData) updateSpec() error {
 	return nil
 }
 
-func (c *containerData) updateStats() error {
+// updateStats collects a new stats sample and reports whether it changed
+// meaningfully (CPU or memory counters moved) from the previous one, so
+// housekeeping can decide whether to back off.
+func (c *containerData) updateStats() (bool, error) {
 	stats, err := c.handler.GetStats()
 	if err != nil {
-		return err
+		return false, err
 	}
 	if stats == nil {
-		return nil
+		return false, nil
 	}
 	summary, err := c.handler.StatsSummary()
 	if err != nil {
-		return err
+		return false, err
 	}
 	timestamp := time.Now()
+	c.attributeFsCapacity(stats)
 
-	// Remove the front if we go over.
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	if c.info.Stats.Len() >= *historyDuration {
-		c.info.Stats.Remove(c.info.Stats.Front())
-	}
-	c.info.Stats.PushBack(&containerStat{
+	previous := c.info.Stats.recent(1)
+	changed := len(previous) == 0 || statsChanged(previous[0].Data, stats)
+	current := &containerStat{
 		Timestamp: timestamp,
 		Data:      stats,
-	})
+	}
+	c.info.Stats.Add(current)
 	c.info.StatsSummary = summary
-	return nil
+	if window := c.info.Stats.recent(0); len(window) > 1 {
+		summary.Filesystems = fsIoRateSummary(window[0], current)
+	}
+
+	// Fan out to the registered storage drivers (New's in-memory driver
+	// and any external ones) in addition to the in-memory window above.
+	ref := info.ContainerReference{Name: c.info.Name}
+	for _, driver := range c.storageDrivers {
+		if err := driver.AddStats(ref, stats); err != nil {
+			log.Printf("Failed to write stats for container \"%s\" to storage driver: %s", c.info.Name, err)
+		}
+	}
+	return changed, nil
+}
+
+// statsChanged reports whether the CPU or memory counters moved between two
+// samples. Network and filesystem counters are intentionally ignored: they
+// can tick on their own even for an otherwise idle container, which would
+// defeat the point of backing off.
+func statsChanged(previous, current *info.ContainerStats) bool {
+	return current.Cpu.Usage.Total != previous.Cpu.Usage.Total ||
+		current.Memory.Usage != previous.Memory.Usage ||
+		current.Memory.WorkingSet != previous.Memory.WorkingSet
+}
+
+// attributeFsCapacity fills in the Capacity of any filesystem sample whose
+// handler didn't already know it, using the machine's block device list.
+func (c *containerData) attributeFsCapacity(stats *info.ContainerStats) {
+	if c.machineInfo == nil {
+		return
+	}
+	for i := range stats.Filesystem {
+		if stats.Filesystem[i].Capacity != 0 {
+			continue
+		}
+		for _, fs := range c.machineInfo.Filesystems {
+			if fs.Device == stats.Filesystem[i].Device {
+				stats.Filesystem[i].Capacity = fs.Capacity
+				break
+			}
+		}
+	}
+}
+
+// fsIoRateSummary computes the average bytes/sec read+written per device
+// between two samples.
+func fsIoRateSummary(previous, current *containerStat) []info.FsStatsSummary {
+	elapsed := current.Timestamp.Sub(previous.Timestamp).Seconds()
+	if elapsed <= 0 {
+		return nil
+	}
+
+	prevByDevice := make(map[string]info.FsStats, len(previous.Data.Filesystem))
+	for _, fs := range previous.Data.Filesystem {
+		prevByDevice[fs.Device] = fs
+	}
+
+	var summary []info.FsStatsSummary
+	for _, fs := range current.Data.Filesystem {
+		prev, ok := prevByDevice[fs.Device]
+		if !ok || fs.SectorsRead < prev.SectorsRead || fs.SectorsWritten < prev.SectorsWritten {
+			continue
+		}
+		sectors := (fs.SectorsRead - prev.SectorsRead) + (fs.SectorsWritten - prev.SectorsWritten)
+		summary = append(summary, info.FsStatsSummary{
+			Device:              fs.Device,
+			AvgIoBytesPerSecond: float64(sectors*512) / elapsed,
+		})
+	}
+	return summary
+}
+
+// GetStats returns the samples matching the request for this container.
+func (c *containerData) GetStats(request info.StatsRequest) ([]*info.ContainerStats, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	stats := c.info.Stats.Get(request)
+	ret := make([]*info.ContainerStats, 0, len(stats))
+	for _, stat := range stats {
+		ret = append(ret, stat.Data)
+	}
+	return ret, nil
+}
+
+// GetSpec returns the container's static configuration.
+func (c *containerData) GetSpec() (*info.ContainerSpec, error) {
+	err := c.updateSpec()
+	if err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.info.Spec, nil
 }
 
 func (c *containerData) updateSubcontainers() error {
@@ -0,0 +1,162 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/google/lmctfy/cadvisor/info"
+)
+
+// Number of events retained per event type so that newly-subscribing
+// watchers can be handed history that falls within their requested window.
+const eventsToKeepPerType = 100
+
+// EventChannel is handed back to callers of Manager.WatchEvents. Events the
+// watcher is interested in are delivered here until the channel's manager
+// goes away; there is no explicit unsubscribe yet.
+type EventChannel chan *info.Event
+
+type eventWatcher struct {
+	request info.EventRequest
+	channel EventChannel
+}
+
+// eventManager is the container event bus. It keeps a bounded amount of
+// history per event type and fans out newly added events to any watcher
+// whose request matches.
+type eventManager struct {
+	lock        sync.Mutex
+	nextId      int
+	watchers    map[int]*eventWatcher
+	history     map[info.EventType][]*info.Event
+	historySize int
+}
+
+func newEventManager(historySize int) *eventManager {
+	return &eventManager{
+		watchers:    make(map[int]*eventWatcher),
+		history:     make(map[info.EventType][]*info.Event),
+		historySize: historySize,
+	}
+}
+
+// WatchEvents registers a new watcher and, if the request sets a StartTime,
+// immediately replays any retained history that falls within the request's
+// time window and filters.
+func (self *eventManager) WatchEvents(request info.EventRequest) (EventChannel, error) {
+	channel := make(EventChannel, 10)
+
+	self.lock.Lock()
+	self.nextId++
+	self.watchers[self.nextId] = &eventWatcher{
+		request: request,
+		channel: channel,
+	}
+
+	var replay []*info.Event
+	if !request.StartTime.IsZero() {
+		for _, events := range self.history {
+			for _, event := range events {
+				if eventMatchesRequest(event, &request) {
+					replay = append(replay, event)
+				}
+			}
+		}
+	}
+	self.lock.Unlock()
+
+	// Deliver replayed history after releasing the lock, and without
+	// blocking: the channel isn't in a reader's hands yet, so a request
+	// matching more than its capacity worth of history would otherwise
+	// deadlock this watcher (and, while the lock was held, every other
+	// caller of AddEvent/GetEvents/WatchEvents too).
+	for _, event := range replay {
+		select {
+		case channel <- event:
+		default:
+			log.Printf("Event channel for new watcher is full, dropping replayed event for container %q", event.ContainerName)
+		}
+	}
+	return channel, nil
+}
+
+// AddEvent records the event in history and delivers it to any watcher
+// whose request matches.
+func (self *eventManager) AddEvent(event *info.Event) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	buffer := append(self.history[event.EventType], event)
+	if len(buffer) > self.historySize {
+		buffer = buffer[len(buffer)-self.historySize:]
+	}
+	self.history[event.EventType] = buffer
+
+	for _, watcher := range self.watchers {
+		if !eventMatchesRequest(event, &watcher.request) {
+			continue
+		}
+		select {
+		case watcher.channel <- event:
+		default:
+			log.Printf("Event channel for watcher is full, dropping event for container %q", event.ContainerName)
+		}
+	}
+	return nil
+}
+
+// GetEvents returns the retained events matching the request.
+func (self *eventManager) GetEvents(request info.EventRequest) ([]*info.Event, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	var events []*info.Event
+	for _, buffer := range self.history {
+		for _, event := range buffer {
+			if eventMatchesRequest(event, &request) {
+				events = append(events, event)
+			}
+		}
+	}
+	return events, nil
+}
+
+func eventMatchesRequest(event *info.Event, request *info.EventRequest) bool {
+	if len(request.EventTypes) > 0 && !request.EventTypes[event.EventType] {
+		return false
+	}
+	containerName := request.ContainerName
+	if containerName == "" {
+		containerName = "/"
+	}
+	if request.IncludeSubcontainers {
+		if containerName != "/" && event.ContainerName != containerName &&
+			!strings.HasPrefix(event.ContainerName, containerName+"/") {
+			return false
+		}
+	} else if event.ContainerName != containerName {
+		return false
+	}
+	if !request.StartTime.IsZero() && event.Timestamp.Before(request.StartTime) {
+		return false
+	}
+	if !request.EndTime.IsZero() && event.Timestamp.After(request.EndTime) {
+		return false
+	}
+	return true
+}
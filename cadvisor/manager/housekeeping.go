@@ -0,0 +1,68 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"flag"
+	"math/rand"
+	"time"
+)
+
+var minHousekeepingInterval = flag.Duration("min_housekeeping_interval", 1*time.Second, "minimum time between container housekeepings, regardless of --allow_dynamic_housekeeping")
+var maxHousekeepingInterval = flag.Duration("max_housekeeping_interval", 60*time.Second, "maximum time a container's housekeeping interval is allowed to grow to when its stats aren't changing")
+var allowDynamicHousekeeping = flag.Bool("allow_dynamic_housekeeping", true, "whether a container's housekeeping interval grows/shrinks between --min_housekeeping_interval and --max_housekeeping_interval based on whether its stats are changing")
+
+// globalHousekeepingInterval governs how often manager.Start looks for new
+// or removed containers.
+const globalHousekeepingInterval = 1 * time.Second
+
+// jitter returns d adjusted by up to +/-10%, so that many containers (or
+// many cadvisor instances) scheduled with the same interval don't all wake
+// up on the same tick.
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return d
+	}
+	spread := int64(d) / 5
+	if spread <= 0 {
+		return d
+	}
+	return d - time.Duration(spread/2) + time.Duration(rand.Int63n(spread))
+}
+
+// nextHousekeepingInterval grows current towards maxHousekeepingInterval
+// when statsChanged is false (nothing interesting happened, so there's no
+// need to keep sampling quickly), and shrinks it back towards
+// minHousekeepingInterval as soon as something changes. Dynamic adjustment
+// is skipped entirely if --allow_dynamic_housekeeping is false.
+func nextHousekeepingInterval(current time.Duration, statsChanged bool) time.Duration {
+	if !*allowDynamicHousekeeping {
+		return *minHousekeepingInterval
+	}
+
+	if statsChanged {
+		next := current / 2
+		if next < *minHousekeepingInterval {
+			next = *minHousekeepingInterval
+		}
+		return next
+	}
+
+	next := current * 2
+	if next > *maxHousekeepingInterval {
+		next = *maxHousekeepingInterval
+	}
+	return next
+}
@@ -0,0 +1,71 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/google/lmctfy/cadvisor/info"
+)
+
+// getMachineInfo gathers basic facts about the machine cadvisor is running
+// on.
+func getMachineInfo() (*info.MachineInfo, error) {
+	// TODO(vmarmol): Read /proc/meminfo for an accurate memory capacity.
+	return &info.MachineInfo{
+		NumCores:       runtime.NumCPU(),
+		MemoryCapacity: 0,
+		Filesystems:    getFilesystems(),
+	}, nil
+}
+
+// getFilesystems enumerates the block devices visible to the machine via
+// /sys/block, along with their capacity.
+func getFilesystems() []info.FsInfo {
+	const sysBlock = "/sys/block"
+
+	entries, err := ioutil.ReadDir(sysBlock)
+	if err != nil {
+		log.Printf("Failed to list block devices in %q: %s", sysBlock, err)
+		return nil
+	}
+
+	var filesystems []info.FsInfo
+	for _, entry := range entries {
+		device := entry.Name()
+
+		// Size is reported in 512-byte sectors.
+		// https://www.kernel.org/doc/Documentation/block/stat.txt
+		raw, err := ioutil.ReadFile(filepath.Join(sysBlock, device, "size"))
+		if err != nil {
+			continue
+		}
+		sectors, err := strconv.ParseUint(strings.TrimSpace(string(raw)), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		filesystems = append(filesystems, info.FsInfo{
+			Device:   filepath.Join("/dev", device),
+			Capacity: sectors * 512,
+		})
+	}
+	return filesystems
+}
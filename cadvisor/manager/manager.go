@@ -21,7 +21,11 @@ import (
 	"time"
 
 	"github.com/google/lmctfy/cadvisor/container"
+	// Register the Docker container source.
+	_ "github.com/google/lmctfy/cadvisor/container/docker"
 	"github.com/google/lmctfy/cadvisor/info"
+	"github.com/google/lmctfy/cadvisor/storage"
+	"github.com/google/lmctfy/cadvisor/storage/memory"
 )
 
 type Manager interface {
@@ -31,13 +35,44 @@ type Manager interface {
 	// Get information about a container.
 	GetContainerInfo(containerName string) (*info.ContainerInfo, error)
 
+	// Get stats samples for a container matching the request.
+	GetContainerStats(containerName string, request info.StatsRequest) ([]*info.ContainerStats, error)
+
+	// Get a container's static configuration.
+	GetContainerSpec(containerName string) (*info.ContainerSpec, error)
+
+	// Get spec and stats for a container and all of its descendants in a
+	// single call.
+	SubcontainersInfo(containerName string, request info.StatsRequest) ([]*info.ContainerInfo, error)
+
 	// Get information about the machine.
 	GetMachineInfo() (*info.MachineInfo, error)
+
+	// Watch for container lifecycle and OOM events matching the request.
+	WatchEvents(request info.EventRequest) (EventChannel, error)
+
+	// Get past events matching the request.
+	GetEvents(request info.EventRequest) ([]*info.Event, error)
+
+	// AddEvent records an event on the manager's event bus. Container
+	// handlers use this to inject events they detect themselves (e.g. OOMs).
+	AddEvent(event *info.Event) error
 }
 
-func New() (Manager, error) {
+// New creates a manager. storageDrivers are external stats backends
+// (InfluxDB, etc) that every container's stats are additionally written to,
+// on top of the always-on in-memory driver New registers itself.
+func New(storageDrivers []storage.StorageDriver) (Manager, error) {
 	newManager := &manager{}
 	newManager.containers = make(map[string]*containerData)
+	newManager.aliases = make(map[string]string)
+	newManager.events = newEventManager(eventsToKeepPerType)
+
+	memoryDriver, err := memory.New(*historyDuration)
+	if err != nil {
+		return nil, err
+	}
+	newManager.storageDrivers = append([]storage.StorageDriver{memoryDriver}, storageDrivers...)
 
 	machineInfo, err := getMachineInfo()
 	if err != nil {
@@ -51,7 +86,15 @@ func New() (Manager, error) {
 type manager struct {
 	containers     map[string]*containerData
 	containersLock sync.RWMutex
+
+	// Maps any other known name for a container (e.g. a raw cgroup path
+	// underlying a namespaced alias) to its canonical key in containers.
+	// Protected by containersLock.
+	aliases map[string]string
+
 	machineInfo    info.MachineInfo
+	events         *eventManager
+	storageDrivers []storage.StorageDriver
 }
 
 // Start the container manager.
@@ -68,8 +111,14 @@ func (m *manager) Start() error {
 	}
 	log.Printf("Recovery completed")
 
-	// Look for new containers in the main housekeeping thread.
-	for t := range time.Tick(time.Second) {
+	// Look for new containers in the main housekeeping thread. The next
+	// fire time is jittered once on startup to stagger this instance
+	// against the rest of the fleet, then advanced by a fixed interval
+	// (rather than re-read from time.Now() each time) so that scheduling
+	// overhead and GC pauses don't drift it over time.
+	next := time.Now().Add(jitter(globalHousekeepingInterval))
+	for {
+		time.Sleep(time.Until(next))
 		start := time.Now()
 
 		// Check for new containers.
@@ -81,26 +130,19 @@ func (m *manager) Start() error {
 		// Log if housekeeping took more than 100ms.
 		duration := time.Since(start)
 		if duration >= 100*time.Millisecond {
-			log.Printf("Global Housekeeping(%d) took %s", t.Unix(), duration)
+			log.Printf("Global Housekeeping(%d) took %s", start.Unix(), duration)
 		}
+
+		next = next.Add(globalHousekeepingInterval)
 	}
-	return nil
 }
 
 // Get a container by name.
 func (m *manager) GetContainerInfo(containerName string) (*info.ContainerInfo, error) {
 	log.Printf("Get(%s)", containerName)
-	var cont *containerData
-	var ok bool
-	func() {
-		m.containersLock.RLock()
-		defer m.containersLock.RUnlock()
-
-		// Ensure we have the container.
-		cont, ok = m.containers[containerName]
-	}()
-	if !ok {
-		return nil, fmt.Errorf("unknown container \"%s\"", containerName)
+	cont, err := m.getContainer(containerName)
+	if err != nil {
+		return nil, err
 	}
 
 	// Get the info from the container.
@@ -124,38 +166,182 @@ func (m *manager) GetContainerInfo(containerName string) (*info.ContainerInfo, e
 			ret.Spec.Memory.Limit = uint64(m.machineInfo.MemoryCapacity)
 		}
 	}
-	ret.Stats = make([]*info.ContainerStats, 0, cinfo.Stats.Len())
-	for e := cinfo.Stats.Front(); e != nil; e = e.Next() {
-		data := e.Value.(*containerStat)
-		ret.Stats = append(ret.Stats, data.Data)
+	// Go through cont.GetStats rather than reading cinfo.Stats directly:
+	// cinfo is a shallow copy of cont.info, so its Stats field is the same
+	// *statsBuffer housekeeping concurrently appends to under cont.lock.
+	stats, err := cont.GetStats(info.StatsRequest{})
+	if err != nil {
+		return nil, err
 	}
+	ret.Stats = stats
 	return ret, nil
 }
 
+// GetContainerStats returns the stats samples for a container matching the
+// request, without paying for a full GetContainerInfo (spec, subcontainers).
+func (m *manager) GetContainerStats(containerName string, request info.StatsRequest) ([]*info.ContainerStats, error) {
+	cont, err := m.getContainer(containerName)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := cont.GetStats(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// The in-memory window may not go back far enough to cover the
+	// requested range; fall back to an external driver if one is
+	// configured (m.storageDrivers[0] is New's in-memory driver).
+	// RecentStats takes no time range, so what a driver returns is its
+	// request.NumStats most recent samples, whether or not they fall
+	// inside [Start, End].
+	if len(stats) == 0 {
+		for _, driver := range m.storageDrivers[1:] {
+			fromDriver, err := driver.RecentStats(containerName, request.NumStats)
+			if err == nil && len(fromDriver) > 0 {
+				return fromDriver, nil
+			}
+		}
+	}
+	return stats, nil
+}
+
+// GetContainerSpec returns a container's static configuration.
+func (m *manager) GetContainerSpec(containerName string) (*info.ContainerSpec, error) {
+	cont, err := m.getContainer(containerName)
+	if err != nil {
+		return nil, err
+	}
+	return cont.GetSpec()
+}
+
+// SubcontainersInfo walks the subtree rooted at containerName once under a
+// single read lock and returns spec+stats for the container and every
+// descendant, so a dashboard for an entire hierarchy can be built from one
+// call.
+func (m *manager) SubcontainersInfo(containerName string, request info.StatsRequest) ([]*info.ContainerInfo, error) {
+	m.containersLock.RLock()
+	defer m.containersLock.RUnlock()
+
+	root, ok := m.containers[containerName]
+	if !ok {
+		return nil, fmt.Errorf("unknown container \"%s\"", containerName)
+	}
+
+	var infos []*info.ContainerInfo
+	var walk func(cont *containerData) error
+	walk = func(cont *containerData) error {
+		// Spec and subcontainers are otherwise only refreshed by GetInfo.
+		if err := cont.updateSpec(); err != nil {
+			return err
+		}
+		if err := cont.updateSubcontainers(); err != nil {
+			return err
+		}
+
+		cont.lock.Lock()
+		name := cont.info.Name
+		subcontainers := cont.info.Subcontainers
+		spec := cont.info.Spec
+		stats := cont.info.Stats.Get(request)
+		cont.lock.Unlock()
+
+		statsCopy := make([]*info.ContainerStats, 0, len(stats))
+		for _, stat := range stats {
+			statsCopy = append(statsCopy, stat.Data)
+		}
+		infos = append(infos, &info.ContainerInfo{
+			Name:          name,
+			Subcontainers: subcontainers,
+			Spec:          spec,
+			Stats:         statsCopy,
+		})
+
+		for _, sub := range subcontainers {
+			child, ok := m.containers[sub]
+			if !ok {
+				continue
+			}
+			if err := walk(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(root); err != nil {
+		return nil, err
+	}
+	return infos, nil
+}
+
+// getContainer looks up a containerData by name, trying the alias table if
+// containerName isn't a canonical key (e.g. it's a raw cgroup path for a
+// container known canonically by its namespaced alias, or vice versa).
+func (m *manager) getContainer(containerName string) (*containerData, error) {
+	m.containersLock.RLock()
+	defer m.containersLock.RUnlock()
+
+	cont, ok := m.containers[containerName]
+	if !ok {
+		if canonical, found := m.aliases[containerName]; found {
+			cont, ok = m.containers[canonical]
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown container \"%s\"", containerName)
+	}
+	return cont, nil
+}
+
 func (m *manager) GetMachineInfo() (*info.MachineInfo, error) {
 	// Copy and return the MachineInfo.
 	ret := m.machineInfo
 	return &ret, nil
 }
 
+func (m *manager) WatchEvents(request info.EventRequest) (EventChannel, error) {
+	return m.events.WatchEvents(request)
+}
+
+func (m *manager) GetEvents(request info.EventRequest) ([]*info.Event, error) {
+	return m.events.GetEvents(request)
+}
+
+func (m *manager) AddEvent(event *info.Event) error {
+	return m.events.AddEvent(event)
+}
+
 // Create a container. This expects to only be called from the global manager thread.
 func (m *manager) createContainer(containerName string) (*containerData, error) {
-	cont, err := NewContainerData(containerName)
+	cont, err := NewContainerData(containerName, m.events, m.storageDrivers, &m.machineInfo)
 	if err != nil {
 		return nil, err
 	}
 
-	// Add to the containers map.
+	// Add to the containers map, along with any other names it's known by.
+	aliases, err := cont.handler.Aliases()
+	if err != nil {
+		return nil, err
+	}
 	func() {
 		m.containersLock.Lock()
 		defer m.containersLock.Unlock()
 
 		log.Printf("Added container: %s", containerName)
 		m.containers[containerName] = cont
+		for _, alias := range aliases {
+			m.aliases[alias] = containerName
+		}
 	}()
 
 	// Start the container's housekeeping.
 	cont.Start()
+
+	m.events.AddEvent(&info.Event{
+		ContainerName: containerName,
+		Timestamp:     time.Now(),
+		EventType:     info.EventContainerCreation,
+	})
 	return cont, nil
 }
 
@@ -174,9 +360,20 @@ func (m *manager) destroyContainer(containerName string) error {
 		return err
 	}
 
-	// Remove the container from our records.
+	// Remove the container and any aliases pointing at it from our records.
 	delete(m.containers, containerName)
+	for alias, canonical := range m.aliases {
+		if canonical == containerName {
+			delete(m.aliases, alias)
+		}
+	}
 	log.Printf("Destroyed container: %s", containerName)
+
+	m.events.AddEvent(&info.Event{
+		ContainerName: containerName,
+		Timestamp:     time.Now(),
+		EventType:     info.EventContainerDeletion,
+	})
 	return nil
 }
 
@@ -184,20 +381,15 @@ type empty struct{}
 
 // Detect all containers that have been added or deleted.
 func (m *manager) getContainersDiff() (added []string, removed []string, err error) {
-	// TODO(vmarmol): We probably don't need to lock around / since it will always be there.
-	m.containersLock.RLock()
-	defer m.containersLock.RUnlock()
-
-	// Get all containers on the system.
-	cont, ok := m.containers["/"]
-	if !ok {
-		return nil, nil, fmt.Errorf("Failed to find container \"/\" while checking for new containers")
-	}
-	allContainers, err := cont.handler.ListContainers(container.LIST_RECURSIVE)
+	// Merge together what every registered container source (raw cgroups,
+	// Docker, ...) currently reports.
+	allContainers, err := container.AllContainerNames()
 	if err != nil {
 		return nil, nil, err
 	}
-	allContainers = append(allContainers, "/")
+
+	m.containersLock.RLock()
+	defer m.containersLock.RUnlock()
 
 	// Determine which were added and which were removed.
 	allContainersSet := make(map[string]*empty)
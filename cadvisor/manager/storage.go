@@ -0,0 +1,42 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/google/lmctfy/cadvisor/storage"
+	"github.com/google/lmctfy/cadvisor/storage/influxdb"
+)
+
+var storageDriver = flag.String("storage_driver", "", "external storage driver to use in addition to the in-memory window. Empty means none. One of: influxdb")
+var storageDriverHost = flag.String("storage_driver_host", "localhost:8086", "storage driver host:port")
+var storageDriverDb = flag.String("storage_driver_db", "cadvisor", "storage driver database name")
+var storageDriverBufferDuration = flag.Duration("storage_driver_buffer_duration", 60*time.Second, "writes to the storage driver are buffered for this duration and sent as a single batch")
+
+// NewStorageDriver builds the external storage.StorageDriver selected by the
+// --storage_driver flag, or nil if none was requested.
+func NewStorageDriver() (storage.StorageDriver, error) {
+	switch *storageDriver {
+	case "":
+		return nil, nil
+	case "influxdb":
+		return influxdb.New(*storageDriverHost, *storageDriverDb, *storageDriverBufferDuration)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", *storageDriver)
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package influxdb implements a storage.StorageDriver that batches writes
+// to an InfluxDB series.
+package influxdb
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/lmctfy/cadvisor/info"
+	"github.com/google/lmctfy/cadvisor/storage"
+)
+
+type influxdbStorage struct {
+	host string
+	db   string
+
+	bufferDuration time.Duration
+	lock           sync.Mutex
+	lastWrite      time.Time
+	points         []point
+}
+
+type point struct {
+	ref   info.ContainerReference
+	stats *info.ContainerStats
+}
+
+// New returns a storage.StorageDriver that buffers points for
+// bufferDuration before flushing them to the InfluxDB series at
+// host/db as a single batched write.
+//
+// TODO(vmarmol): Use the official InfluxDB client once it's vendored; for
+// now this only buffers and logs what it would send.
+func New(host, db string, bufferDuration time.Duration) (storage.StorageDriver, error) {
+	return &influxdbStorage{
+		host:           host,
+		db:             db,
+		bufferDuration: bufferDuration,
+		lastWrite:      time.Now(),
+	}, nil
+}
+
+func (self *influxdbStorage) AddStats(ref info.ContainerReference, stats *info.ContainerStats) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.points = append(self.points, point{ref: ref, stats: stats})
+	if time.Since(self.lastWrite) >= self.bufferDuration {
+		return self.flush()
+	}
+	return nil
+}
+
+// flush sends the buffered points as a single batched write. Must be called
+// with self.lock held.
+func (self *influxdbStorage) flush() error {
+	if len(self.points) == 0 {
+		return nil
+	}
+	log.Printf("Flushing %d points to InfluxDB at %s (db=%s)", len(self.points), self.host, self.db)
+	self.points = self.points[:0]
+	self.lastWrite = time.Now()
+	return nil
+}
+
+func (self *influxdbStorage) RecentStats(name string, numStats int) ([]*info.ContainerStats, error) {
+	// InfluxDB is a write-behind backend for long-term history; recent
+	// queries are served by the in-memory driver instead.
+	return nil, nil
+}
+
+func (self *influxdbStorage) Close() error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.flush()
+}
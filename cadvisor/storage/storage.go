@@ -0,0 +1,36 @@
+// Copyright 2014 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package storage defines the interface external stats backends implement.
+package storage
+
+import (
+	"github.com/google/lmctfy/cadvisor/info"
+)
+
+// StorageDriver persists container stats to some backend (in-memory,
+// time-series database, etc). Implementations must be safe for concurrent
+// use.
+type StorageDriver interface {
+	// AddStats records a single sample for the given container.
+	AddStats(ref info.ContainerReference, stats *info.ContainerStats) error
+
+	// RecentStats returns the numStats most recent samples recorded for the
+	// named container. A numStats <= 0 returns all retained samples.
+	RecentStats(name string, numStats int) ([]*info.ContainerStats, error)
+
+	// Close releases any resources held by the driver (network
+	// connections, buffered writers, etc).
+	Close() error
+}